@@ -1,6 +1,7 @@
 package dt
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"encoding/json"
@@ -11,7 +12,7 @@ import (
 	log "github.com/avabot/ava/Godeps/_workspace/src/github.com/Sirupsen/logrus"
 	"github.com/avabot/ava/Godeps/_workspace/src/github.com/dchest/stemmer/porter2"
 	"github.com/avabot/ava/Godeps/_workspace/src/github.com/jbrukh/bayesian"
-	"github.com/avabot/ava/Godeps/_workspace/src/github.com/jmoiron/sqlx"
+	"github.com/avabot/ava/shared/datatypes/textin"
 	"github.com/avabot/ava/shared/nlp"
 )
 
@@ -34,12 +35,24 @@ type Msg struct {
 	// SentenceFields breaks the sentence into words. Tokens like ,.' are
 	// treated as individual words.
 	SentenceFields []string
+	// OriginalSentence holds the message as the user sent it, before
+	// textin.Normalize stripped any HTML, so plugins can still inspect
+	// links or quoted replies.
+	OriginalSentence string
+	// TextMeta describes what textin.Normalize found while cleaning
+	// OriginalSentence into Sentence (e.g. whether it was HTML, and any
+	// links it contained).
+	TextMeta textin.Meta
 }
 
 // RespMsg is used to pass results from packages to Ava
 type RespMsg struct {
 	MsgID    uint64
 	Sentence string
+	// PushPreferred marks a response for delivery through a push
+	// transport (dt/notify) rather than as a reply in the channel the
+	// triggering message arrived on.
+	PushPreferred bool
 }
 
 type Feedback struct {
@@ -67,8 +80,20 @@ func (j *jsonState) Value() (driver.Value, error) {
 	return j, nil
 }
 
-func NewMsg(db *sqlx.DB, bayes *bayesian.Classifier, u *User, cmd string) *Msg {
-	words := strings.Fields(cmd)
+// NewMsg builds a Msg from a raw user command. It has no way to abort the
+// DB work addContext does on the caller's behalf; prefer NewMsgContext in new
+// code.
+func NewMsg(s Store, bayes *bayesian.Classifier, u *User, cmd string) *Msg {
+	return NewMsgContext(context.Background(), s, bayes, u, cmd)
+}
+
+// NewMsgContext behaves like NewMsg, but aborts the context lookup that
+// resolves pronouns (addContext) as soon as ctx is canceled or its deadline
+// passes.
+func NewMsgContext(ctx context.Context, s Store, bayes *bayesian.Classifier,
+	u *User, cmd string) *Msg {
+	clean, meta := textin.Normalize(cmd, "")
+	words := strings.Fields(clean)
 	eng := porter2.Stemmer
 	stems := []string{}
 	for _, w := range words {
@@ -76,31 +101,39 @@ func NewMsg(db *sqlx.DB, bayes *bayesian.Classifier, u *User, cmd string) *Msg {
 		stems = append(stems, eng.Stem(w))
 	}
 	// TODO handle training here with the _ var
-	si, annotated, _, err := nlp.Classify(bayes, cmd)
+	si, annotated, _, err := nlp.Classify(bayes, clean)
 	if err != nil {
 		log.Errorln("classifying sentence", err)
 	}
 	m := &Msg{
 		User:              u,
-		Sentence:          cmd,
-		SentenceFields:    SentenceFields(cmd),
+		Sentence:          clean,
+		OriginalSentence:  cmd,
+		TextMeta:          meta,
+		SentenceFields:    SentenceFields(clean),
 		Stems:             stems,
 		StructuredInput:   si,
 		SentenceAnnotated: annotated,
 	}
-	m, err = addContext(db, m)
+	m, err = addContext(ctx, s, m)
 	if err != nil {
 		log.WithField("fn", "addContext").Errorln(err)
 	}
 	return m
 }
 
-func (m *Msg) GetLastRoute(db *sqlx.DB) (string, error) {
+func (m *Msg) GetLastRoute(s Store) (string, error) {
+	return m.GetLastRouteContext(context.Background(), s)
+}
+
+// GetLastRouteContext behaves like GetLastRoute, but aborts the underlying
+// query as soon as ctx is canceled or its deadline passes.
+func (m *Msg) GetLastRouteContext(ctx context.Context, s Store) (string, error) {
 	var route string
-	q := `SELECT route FROM messages
+	q := s.Rebind(`SELECT route FROM messages
 	      WHERE userid=$1
-	      ORDER BY createdat DESC`
-	err := db.Get(&route, q, m.User.ID)
+	      ORDER BY createdat DESC`)
+	err := s.DB().GetContext(ctx, &route, q, m.User.ID)
 	if err != nil && err != sql.ErrNoRows {
 		return "", err
 	}
@@ -193,98 +226,118 @@ func (m *Msg) NewResponse() *Resp {
 
 // addContext to a StructuredInput, replacing pronouns with the nouns to which
 // they refer. TODO refactor
-func addContext(db *sqlx.DB, m *Msg) (*Msg, error) {
+func addContext(ctx context.Context, s Store, m *Msg) (*Msg, error) {
 	for _, w := range m.StructuredInput.Pronouns() {
-		var ctx string
+		var found string
 		var err error
 		switch nlp.Pronouns[w] {
 		case nlp.ObjectI:
-			ctx, err = getContextObject(db, m.User,
+			found, err = getContextObject(ctx, s, m.User,
 				m.StructuredInput, "objects")
 			if err != nil {
 				return m, err
 			}
-			if ctx == "" {
+			if found == "" {
 				return m, nil
 			}
 			for i, o := range m.StructuredInput.Objects {
 				if o != w {
 					continue
 				}
-				m.StructuredInput.Objects[i] = ctx
+				m.StructuredInput.Objects[i] = found
 			}
 		case nlp.ActorI:
-			ctx, err = getContextObject(db, m.User,
+			found, err = getContextObject(ctx, s, m.User,
 				m.StructuredInput, "actors")
 			if err != nil {
 				return m, err
 			}
-			if ctx == "" {
+			if found == "" {
 				return m, nil
 			}
 			for i, o := range m.StructuredInput.Actors {
 				if o != w {
 					continue
 				}
-				m.StructuredInput.Actors[i] = ctx
+				m.StructuredInput.Actors[i] = found
 			}
 		case nlp.TimeI:
-			ctx, err = getContextObject(db, m.User,
+			found, err = getContextObject(ctx, s, m.User,
 				m.StructuredInput, "times")
 			if err != nil {
 				return m, err
 			}
-			if ctx == "" {
+			if found == "" {
 				return m, nil
 			}
 			for i, o := range m.StructuredInput.Times {
 				if o != w {
 					continue
 				}
-				m.StructuredInput.Times[i] = ctx
+				m.StructuredInput.Times[i] = found
 			}
 		case nlp.PlaceI:
-			ctx, err = getContextObject(db, m.User,
+			found, err = getContextObject(ctx, s, m.User,
 				m.StructuredInput, "places")
 			if err != nil {
 				return m, err
 			}
-			if ctx == "" {
+			if found == "" {
 				return m, nil
 			}
 			for i, o := range m.StructuredInput.Places {
 				if o != w {
 					continue
 				}
-				m.StructuredInput.Places[i] = ctx
+				m.StructuredInput.Places[i] = found
 			}
 		default:
 			return m, errors.New("unknown type found for pronoun")
 		}
 		log.WithFields(log.Fields{
 			"fn":  "addContext",
-			"ctx": ctx,
+			"ctx": found,
 		}).Infoln("context found")
 	}
 	return m, nil
 }
 
-func getContextObject(db *sqlx.DB, u *User, si *nlp.StructuredInput,
-	datatype string) (string, error) {
+// getContextObject fetches the most recent value the user supplied for
+// datatype ("objects", "actors", "times", or "places"), which a pronoun in
+// the current sentence is assumed to refer back to. The `objects` column is
+// used as the row filter regardless of datatype since any non-empty input
+// row is a candidate antecedent.
+//
+// datatype is stored as a JSON array (JSONB on Postgres, a JSON-encoded TEXT
+// column on SQLite), so the row is decoded with encoding/json here rather
+// than scanned into nlp.StringSlice, which was written for the old native
+// Postgres TEXT[] column and doesn't understand either dialect's JSON
+// representation.
+func getContextObject(ctx context.Context, s Store, u *User,
+	si *nlp.StructuredInput, datatype string) (string, error) {
 	log.Debugln("getting object context")
-	var tmp *nlp.StringSlice
 	if u == nil {
 		return "", ErrMissingUser
 	}
-	if u != nil {
-		q := `SELECT ` + datatype + `
-		      FROM inputs
-		      WHERE userid=$1 AND array_length(objects, 1) > 0`
-		if err := db.Get(&tmp, q, u.ID); err != nil {
-			return "", err
+	var raw []byte
+	q := s.Rebind(`SELECT ` + datatype + `
+	      FROM inputs
+	      WHERE userid=$1 AND ` + s.JSONArrayLength("objects") + ` > 0
+	      ORDER BY createdat DESC`)
+	if err := s.DB().GetContext(ctx, &raw, q, u.ID); err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
 		}
+		return "", err
+	}
+	var vals []string
+	if err := json.Unmarshal(raw, &vals); err != nil {
+		return "", err
+	}
+	if len(vals) == 0 {
+		return "", nil
 	}
-	return tmp.Last(), nil
+	return vals[len(vals)-1], nil
 }
 
 func SentenceFields(s string) []string {
@@ -0,0 +1,325 @@
+// Package cron lets plugins schedule future or recurring RespMsg deliveries
+// tied to a user, e.g. "remind me tomorrow at 9am" or "every Monday tell
+// me...".
+package cron
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	log "github.com/avabot/ava/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	dt "github.com/avabot/ava/shared/datatypes"
+)
+
+// Job is a single scheduled or recurring RespMsg delivery.
+type Job struct {
+	ID      uint64
+	UserID  uint64
+	Route   string
+	Payload map[string]interface{}
+	When    time.Time
+	// Every is the recurrence interval. Zero means the job fires once
+	// and is removed.
+	Every time.Duration
+}
+
+// jobState is the in-memory mirror of pending jobs, guarded by Use so
+// Add/Remove from plugin goroutines can't race with the Run loop.
+type jobState struct {
+	jobs map[uint64]Job
+}
+
+// Deliver hands a ripe job's RespMsg to Ava's normal message pipeline, the
+// same one NewMsg feeds responses into.
+type Deliver func(ctx context.Context, userID uint64, resp dt.RespMsg) error
+
+// Handler builds the RespMsg for a ripe job on a given route. Plugin authors
+// register one per route via Scheduler.Handle, alongside their normal
+// routes.
+type Handler func(ctx context.Context, job Job) (dt.RespMsg, error)
+
+// Scheduler runs the cron loop, sleeping until the next job is ripe and then
+// draining it (and any others that became ripe at the same time) into
+// Deliver.
+type Scheduler struct {
+	store    dt.Store
+	deliver  Deliver
+	mu       sync.Mutex
+	state    jobState
+	handlers map[string]Handler
+
+	// wake lets Add/Remove interrupt a Run loop already parked on a
+	// stale timer, so a job scheduled sooner than the current wait fires
+	// on time instead of waiting out the old timer. Buffered 1 so a
+	// signal sent while Run is busy elsewhere isn't lost or blocking.
+	wake chan struct{}
+}
+
+// New creates a Scheduler backed by s. deliver is called once per ripe job
+// to hand its RespMsg back into Ava's pipeline.
+func New(s dt.Store, deliver Deliver) *Scheduler {
+	return &Scheduler{
+		store:    s,
+		deliver:  deliver,
+		state:    jobState{jobs: map[uint64]Job{}},
+		handlers: map[string]Handler{},
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// notifyWake wakes a parked Run loop so it recomputes its timer against the
+// latest job state. It never blocks: if a wake is already pending, Run will
+// see it and re-check anyway.
+func (s *Scheduler) notifyWake() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Handle registers the Handler plugins use to build the RespMsg fired for
+// jobs scheduled against route.
+func (s *Scheduler) Handle(route string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[route] = h
+}
+
+// handler looks up the Handler registered for route, guarding the read
+// against a concurrent Handle the same way Use guards the job map.
+func (s *Scheduler) handler(route string) (Handler, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.handlers[route]
+	return h, ok
+}
+
+// Use runs fn with exclusive access to the scheduler's in-memory job state,
+// so concurrent Add/Remove calls don't race with the Run loop reading the
+// same map.
+func (s *Scheduler) Use(fn func(jobs map[uint64]Job) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn(s.state.jobs)
+}
+
+// Add persists a job for userID and schedules it to fire at when, then every
+// interval thereafter if every is non-zero. Jobs survive process restarts
+// because they're written to the queue table before being added to the
+// in-memory state the runner watches.
+func (s *Scheduler) Add(ctx context.Context, userID uint64, when time.Time,
+	every time.Duration, route string,
+	payload map[string]interface{}) (uint64, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	id, err := s.insert(ctx, userID, route, b, when, every)
+	if err != nil {
+		return 0, err
+	}
+	job := Job{
+		ID:      id,
+		UserID:  userID,
+		Route:   route,
+		Payload: payload,
+		When:    when,
+		Every:   every,
+	}
+	err = s.Use(func(jobs map[uint64]Job) error {
+		jobs[job.ID] = job
+		return nil
+	})
+	s.notifyWake()
+	return job.ID, err
+}
+
+// insert writes a new cron_jobs row and returns its id. Postgres's driver
+// doesn't implement LastInsertId (there's no auto-generated-ID reporting
+// without a RETURNING clause), so Postgres inserts RETURNING id and scans
+// it directly; SQLite inserts normally and reads back LastInsertId.
+func (s *Scheduler) insert(ctx context.Context, userID uint64, route string,
+	payload []byte, when time.Time, every time.Duration) (uint64, error) {
+	if s.store.Dialect() == dt.DialectPostgres {
+		q := `INSERT INTO cron_jobs
+		      (userid, route, payload, runat, everyns)
+		      VALUES ($1, $2, $3, $4, $5)
+		      RETURNING id`
+		var id uint64
+		err := s.store.DB().QueryRowContext(ctx, q, userID, route, payload,
+			when, int64(every)).Scan(&id)
+		return id, err
+	}
+	q := s.store.Rebind(`INSERT INTO cron_jobs
+	      (userid, route, payload, runat, everyns)
+	      VALUES ($1, $2, $3, $4, $5)`)
+	res, err := s.store.DB().ExecContext(ctx, q, userID, route, payload, when,
+		int64(every))
+	if err != nil {
+		return 0, err
+	}
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(lastID), nil
+}
+
+// Remove cancels a pending job, whether one-shot or recurring.
+func (s *Scheduler) Remove(ctx context.Context, jobID uint64) error {
+	q := s.store.Rebind(`DELETE FROM cron_jobs WHERE id=$1`)
+	if _, err := s.store.DB().ExecContext(ctx, q, jobID); err != nil {
+		return err
+	}
+	err := s.Use(func(jobs map[uint64]Job) error {
+		delete(jobs, jobID)
+		return nil
+	})
+	s.notifyWake()
+	return err
+}
+
+// reschedule advances a recurring job to its next When in place, updating
+// the existing cron_jobs row rather than deleting and re-inserting it, so
+// job.ID (the only handle a caller who scheduled it ever gets back) stays
+// valid across every firing instead of only the first.
+func (s *Scheduler) reschedule(ctx context.Context, job Job) error {
+	q := s.store.Rebind(`UPDATE cron_jobs SET runat=$1, everyns=$2 WHERE id=$3`)
+	if _, err := s.store.DB().ExecContext(ctx, q, job.When,
+		int64(job.Every), job.ID); err != nil {
+		return err
+	}
+	err := s.Use(func(jobs map[uint64]Job) error {
+		jobs[job.ID] = job
+		return nil
+	})
+	s.notifyWake()
+	return err
+}
+
+// load reads all pending jobs from the queue table into memory, so a
+// restarted Scheduler picks up where it left off.
+func (s *Scheduler) load(ctx context.Context) error {
+	rows := []struct {
+		ID      uint64
+		UserID  uint64
+		Route   string
+		Payload []byte
+		RunAt   time.Time
+		EveryNS int64
+	}{}
+	q := `SELECT id, userid, route, payload, runat, everyns FROM cron_jobs`
+	if err := s.store.DB().SelectContext(ctx, &rows, q); err != nil {
+		return err
+	}
+	return s.Use(func(jobs map[uint64]Job) error {
+		for _, r := range rows {
+			var payload map[string]interface{}
+			if err := json.Unmarshal(r.Payload, &payload); err != nil {
+				return err
+			}
+			jobs[r.ID] = Job{
+				ID:      r.ID,
+				UserID:  r.UserID,
+				Route:   r.Route,
+				Payload: payload,
+				When:    r.RunAt,
+				Every:   time.Duration(r.EveryNS),
+			}
+		}
+		return nil
+	})
+}
+
+// nextFireTime returns the earliest When among pending jobs.
+func (s *Scheduler) nextFireTime() (time.Time, bool) {
+	var next time.Time
+	var found bool
+	s.Use(func(jobs map[uint64]Job) error {
+		for _, j := range jobs {
+			if !found || j.When.Before(next) {
+				next = j.When
+				found = true
+			}
+		}
+		return nil
+	})
+	return next, found
+}
+
+// Run drains ripe jobs into the message pipeline until ctx is canceled. It
+// sleeps on a time.Timer set to the next job's fire time, recomputing that
+// timer whenever Add or Remove changes which job is next (via wake) so a
+// job scheduled sooner than the current wait still fires on time, and wakes
+// early whenever ctx is canceled so callers can shut it down cleanly.
+func (s *Scheduler) Run(ctx context.Context) error {
+	if err := s.load(ctx); err != nil {
+		return err
+	}
+	for {
+		wait := time.Hour
+		if next, ok := s.nextFireTime(); ok {
+			if wait = time.Until(next); wait < 0 {
+				wait = 0
+			}
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-s.wake:
+			timer.Stop()
+		case <-timer.C:
+			if err := s.runRipe(ctx); err != nil {
+				log.WithField("fn", "Scheduler.Run").Errorln(err)
+			}
+		}
+	}
+}
+
+// runRipe fires every job whose When has passed, re-enqueuing recurring ones
+// with their next When.
+func (s *Scheduler) runRipe(ctx context.Context) error {
+	now := time.Now()
+	var ripe []Job
+	err := s.Use(func(jobs map[uint64]Job) error {
+		for id, j := range jobs {
+			if j.When.After(now) {
+				continue
+			}
+			ripe = append(ripe, j)
+			delete(jobs, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, j := range ripe {
+		h, ok := s.handler(j.Route)
+		if !ok {
+			log.WithField("route", j.Route).Warnln("no cron handler registered")
+		} else {
+			resp, err := h(ctx, j)
+			if err != nil {
+				log.WithField("fn", "Scheduler.runRipe").Errorln(err)
+			} else if err = s.deliver(ctx, j.UserID, resp); err != nil {
+				log.WithField("fn", "Scheduler.deliver").Errorln(err)
+			}
+		}
+		if j.Every == 0 {
+			if err := s.Remove(ctx, j.ID); err != nil {
+				log.WithField("fn", "Scheduler.Remove").Errorln(err)
+			}
+			continue
+		}
+		j.When = j.When.Add(j.Every)
+		if err := s.reschedule(ctx, j); err != nil {
+			log.WithField("fn", "Scheduler.reschedule").Errorln(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextFireTimeReturnsEarliest(t *testing.T) {
+	s := New(nil, nil)
+	now := time.Now()
+	err := s.Use(func(jobs map[uint64]Job) error {
+		jobs[1] = Job{ID: 1, When: now.Add(time.Hour)}
+		jobs[2] = Job{ID: 2, When: now.Add(time.Minute)}
+		jobs[3] = Job{ID: 3, When: now.Add(24 * time.Hour)}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, ok := s.nextFireTime()
+	if !ok {
+		t.Fatal("nextFireTime() ok = false, want true")
+	}
+	if !next.Equal(now.Add(time.Minute)) {
+		t.Errorf("nextFireTime() = %v, want %v", next, now.Add(time.Minute))
+	}
+}
+
+func TestNextFireTimeEmpty(t *testing.T) {
+	s := New(nil, nil)
+	if _, ok := s.nextFireTime(); ok {
+		t.Error("nextFireTime() ok = true for empty scheduler, want false")
+	}
+}
+
+// TestNotifyWakeIsNonBlocking confirms a Run loop parked on a stale timer
+// can be woken without its sender blocking, and that a second wake while
+// one is already pending doesn't block either (the buffered channel drops
+// it rather than stalling Add/Remove).
+func TestNotifyWakeIsNonBlocking(t *testing.T) {
+	s := New(nil, nil)
+	done := make(chan struct{})
+	go func() {
+		s.notifyWake()
+		s.notifyWake()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("notifyWake blocked")
+	}
+	select {
+	case <-s.wake:
+	default:
+		t.Fatal("expected a pending wake signal")
+	}
+}
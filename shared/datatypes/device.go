@@ -0,0 +1,65 @@
+package dt
+
+import (
+	"context"
+	"time"
+)
+
+// Platform identifies which push service a DeviceToken belongs to.
+type Platform string
+
+const (
+	PlatformAPNs Platform = "apns"
+	PlatformFCM  Platform = "fcm"
+)
+
+// DeviceToken is a single device registered to receive push notifications.
+// Users may have several, across platforms (phone + tablet, iOS + Android).
+type DeviceToken struct {
+	ID        uint64
+	UserID    uint64
+	Platform  Platform
+	Token     string
+	CreatedAt time.Time
+}
+
+// AddDeviceToken registers token for delivery to userID on platform. Adding
+// an already-registered token is a no-op.
+func AddDeviceToken(ctx context.Context, s Store, userID uint64,
+	platform Platform, token string) error {
+	q := s.Rebind(`INSERT INTO device_tokens (userid, platform, token)
+	      VALUES ($1, $2, $3)
+	      ON CONFLICT (platform, token) DO NOTHING`)
+	_, err := s.DB().ExecContext(ctx, q, userID, platform, token)
+	return err
+}
+
+// DeviceTokens returns every token registered to userID, across platforms.
+func DeviceTokens(ctx context.Context, s Store, userID uint64) ([]DeviceToken, error) {
+	var tokens []DeviceToken
+	q := s.Rebind(`SELECT id, userid, platform, token, createdat
+	      FROM device_tokens WHERE userid=$1`)
+	if err := s.DB().SelectContext(ctx, &tokens, q, userID); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// RemoveDeviceToken deletes a token, e.g. after a push transport reports it
+// as no longer valid.
+func RemoveDeviceToken(ctx context.Context, s Store, platform Platform, token string) error {
+	q := s.Rebind(`DELETE FROM device_tokens WHERE platform=$1 AND token=$2`)
+	_, err := s.DB().ExecContext(ctx, q, platform, token)
+	return err
+}
+
+// NewResponse builds a RespMsg from m, optionally preferring delivery
+// through a push transport (dt/notify) over a reply in the originating
+// channel.
+func (m *Msg) NewResponse(sentence string, pushPreferred bool) RespMsg {
+	return RespMsg{
+		MsgID:         m.ID,
+		Sentence:      sentence,
+		PushPreferred: pushPreferred,
+	}
+}
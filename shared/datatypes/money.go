@@ -0,0 +1,8 @@
+package dt
+
+// Money is a monetary amount in a currency's minor units (e.g. cents for
+// USD, nothing for JPY, thousandths for BHD), alongside its ISO-4217 code.
+type Money struct {
+	Amount   int64
+	Currency string
+}
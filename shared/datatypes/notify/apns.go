@@ -0,0 +1,125 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/avabot/ava/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	dt "github.com/avabot/ava/shared/datatypes"
+)
+
+// apnsHost is the production APNs HTTP/2 endpoint. Tests/sandboxes should
+// point at api.sandbox.push.apple.com instead.
+const apnsHost = "https://api.push.apple.com"
+
+// APNsTransport delivers pushes to iOS devices over APNs' HTTP/2 API. It
+// keeps one long-lived, pooled TLS connection (http.Transport handles the
+// reconnects) rather than dialing per-push, and prunes any device token
+// APNs reports as unregistered.
+type APNsTransport struct {
+	store  dt.Store
+	topic  string
+	client *http.Client
+
+	mu sync.Mutex
+}
+
+// NewAPNsTransport builds an APNsTransport that authenticates with cert,
+// a TLS client certificate issued for topic (the app's bundle ID).
+func NewAPNsTransport(store dt.Store, topic string, cert tls.Certificate) *APNsTransport {
+	return &APNsTransport{
+		store: store,
+		topic: topic,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					Certificates: []tls.Certificate{cert},
+				},
+				ForceAttemptHTTP2:   true,
+				MaxIdleConnsPerHost: 10,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+	}
+}
+
+type apnsPayload struct {
+	Aps struct {
+		Alert string `json:"alert"`
+	} `json:"aps"`
+}
+
+// Send pushes msg to every APNs device token registered to user. A token
+// APNs reports as unregistered (HTTP 410, or 400 BadDeviceToken) is pruned
+// from storage so future sends don't retry it.
+func (t *APNsTransport) Send(ctx context.Context, user *dt.User, msg dt.RespMsg) error {
+	tokens, err := dt.DeviceTokens(ctx, t.store, user.ID)
+	if err != nil {
+		return err
+	}
+	payload := apnsPayload{}
+	payload.Aps.Alert = msg.Sentence
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	var sent, attempted bool
+	for _, dev := range tokens {
+		if dev.Platform != dt.PlatformAPNs {
+			continue
+		}
+		attempted = true
+		url := fmt.Sprintf("%s/3/device/%s", apnsHost, dev.Token)
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("apns-topic", t.topic)
+		resp, err := t.client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		prune := resp.StatusCode == http.StatusGone
+		if resp.StatusCode == http.StatusBadRequest {
+			var apnsErr struct {
+				Reason string `json:"reason"`
+			}
+			if json.NewDecoder(resp.Body).Decode(&apnsErr) == nil &&
+				apnsErr.Reason == "BadDeviceToken" {
+				prune = true
+			}
+		}
+		if prune {
+			if err := dt.RemoveDeviceToken(ctx, t.store, dt.PlatformAPNs, dev.Token); err != nil {
+				log.WithField("fn", "APNsTransport.Send").Errorln(err)
+			}
+		}
+		if err := resp.Body.Close(); err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode == http.StatusOK {
+			sent = true
+		} else {
+			lastErr = fmt.Errorf("apns: delivery failed with status %d", resp.StatusCode)
+		}
+	}
+	if sent {
+		return nil
+	}
+	if !attempted {
+		return ErrNoDevices
+	}
+	return lastErr
+}
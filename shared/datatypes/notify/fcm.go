@@ -0,0 +1,105 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/avabot/ava/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	dt "github.com/avabot/ava/shared/datatypes"
+)
+
+const fcmEndpoint = "https://fcm.googleapis.com/v1/projects/%s/messages:send"
+
+// FCMTransport delivers pushes to Android (and web) devices via Firebase
+// Cloud Messaging's HTTP v1 API.
+type FCMTransport struct {
+	store     dt.Store
+	projectID string
+	// accessToken is a short-lived OAuth2 bearer token for the Firebase
+	// service account (FCM tokens expire hourly). It is fixed when the
+	// FCMTransport is built and FCMTransport has no method to replace it;
+	// a refreshed token takes a new FCMTransport from NewFCMTransport.
+	accessToken string
+	client      *http.Client
+}
+
+// NewFCMTransport builds an FCMTransport for the given Firebase project.
+func NewFCMTransport(store dt.Store, projectID, accessToken string) *FCMTransport {
+	return &FCMTransport{
+		store:       store,
+		projectID:   projectID,
+		accessToken: accessToken,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type fcmMessage struct {
+	Message struct {
+		Token        string            `json:"token"`
+		Notification map[string]string `json:"notification"`
+	} `json:"message"`
+}
+
+// Send pushes msg to every FCM device token registered to user. A token FCM
+// reports as unregistered is pruned from storage.
+func (t *FCMTransport) Send(ctx context.Context, user *dt.User, msg dt.RespMsg) error {
+	tokens, err := dt.DeviceTokens(ctx, t.store, user.ID)
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	var sent, attempted bool
+	for _, dev := range tokens {
+		if dev.Platform != dt.PlatformFCM {
+			continue
+		}
+		attempted = true
+		m := fcmMessage{}
+		m.Message.Token = dev.Token
+		m.Message.Notification = map[string]string{"body": msg.Sentence}
+		body, err := json.Marshal(m)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		url := fmt.Sprintf(fcmEndpoint, t.projectID)
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("Authorization", "Bearer "+t.accessToken)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := t.client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			if err := dt.RemoveDeviceToken(ctx, t.store, dt.PlatformFCM, dev.Token); err != nil {
+				log.WithField("fn", "FCMTransport.Send").Errorln(err)
+			}
+		}
+		if err := resp.Body.Close(); err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode == http.StatusOK {
+			sent = true
+		} else {
+			lastErr = fmt.Errorf("fcm: delivery failed with status %d", resp.StatusCode)
+		}
+	}
+	if sent {
+		return nil
+	}
+	if !attempted {
+		return ErrNoDevices
+	}
+	return lastErr
+}
@@ -0,0 +1,41 @@
+// Package notify delivers RespMsg values to a user's devices, outside of
+// whatever channel (SMS, web chat, Slack...) triggered the response.
+package notify
+
+import (
+	"context"
+	"errors"
+
+	dt "github.com/avabot/ava/shared/datatypes"
+)
+
+// ErrNoDevices is returned by a Transport's Send when the user has no
+// device registered for that transport's platform, so a Chain falls
+// through to its next, guaranteed-delivery transport instead of treating
+// the push as having succeeded.
+var ErrNoDevices = errors.New("notify: no device registered for this transport")
+
+// Transport pushes a response to a specific user. Implementations should
+// return a non-nil error only for delivery failures the caller should know
+// about; an unreachable or pruned individual device is not itself an error.
+// Send must return ErrNoDevices, not nil, when the user has no device
+// registered to attempt delivery to.
+type Transport interface {
+	Send(ctx context.Context, user *dt.User, msg dt.RespMsg) error
+}
+
+// Chain tries each Transport in order, moving to the next only if the
+// previous one errors. This backs the APNs/FCM-with-SMTP/SMS-fallback setup:
+// push first, fall back to a guaranteed-delivery channel if push fails.
+type Chain []Transport
+
+// Send delivers msg via the first Transport in the chain to succeed.
+func (c Chain) Send(ctx context.Context, user *dt.User, msg dt.RespMsg) error {
+	var err error
+	for _, t := range c {
+		if err = t.Send(ctx, user, msg); err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	dt "github.com/avabot/ava/shared/datatypes"
+)
+
+type fakeTransport struct {
+	err error
+}
+
+func (f fakeTransport) Send(ctx context.Context, user *dt.User, msg dt.RespMsg) error {
+	return f.err
+}
+
+func TestChainSendFallsThroughOnNoDevices(t *testing.T) {
+	c := Chain{
+		fakeTransport{err: ErrNoDevices},
+		fakeTransport{err: nil},
+	}
+	if err := c.Send(context.Background(), &dt.User{}, dt.RespMsg{}); err != nil {
+		t.Errorf("Send() = %v, want nil (should fall through past ErrNoDevices)", err)
+	}
+}
+
+func TestChainSendFallsThroughOnDeliveryFailure(t *testing.T) {
+	c := Chain{
+		fakeTransport{err: errors.New("apns: delivery failed with status 500")},
+		fakeTransport{err: nil},
+	}
+	if err := c.Send(context.Background(), &dt.User{}, dt.RespMsg{}); err != nil {
+		t.Errorf("Send() = %v, want nil (should fall through past a delivery failure, not just ErrNoDevices)", err)
+	}
+}
+
+func TestChainSendReturnsLastErrorWhenAllFail(t *testing.T) {
+	wantErr := errors.New("smtp: connection refused")
+	c := Chain{
+		fakeTransport{err: ErrNoDevices},
+		fakeTransport{err: wantErr},
+	}
+	if err := c.Send(context.Background(), &dt.User{}, dt.RespMsg{}); err != wantErr {
+		t.Errorf("Send() = %v, want %v", err, wantErr)
+	}
+}
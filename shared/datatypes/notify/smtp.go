@@ -0,0 +1,36 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+
+	dt "github.com/avabot/ava/shared/datatypes"
+)
+
+// SMTPTransport is the guaranteed-delivery fallback used when push fails or
+// a user has no registered devices: a plain email to the address on file.
+type SMTPTransport struct {
+	addr string // host:port of the SMTP relay
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPTransport builds an SMTPTransport that relays through addr,
+// authenticating with auth, and sending from the given address.
+func NewSMTPTransport(addr string, auth smtp.Auth, from string) *SMTPTransport {
+	return &SMTPTransport{addr: addr, auth: auth, from: from}
+}
+
+// Send emails msg.Sentence to user.Email. It returns an error immediately if
+// the user has no email on file, so a Chain can fall through to the next
+// transport.
+func (t *SMTPTransport) Send(ctx context.Context, user *dt.User, msg dt.RespMsg) error {
+	if user.Email == "" {
+		return fmt.Errorf("notify: user %d has no email on file", user.ID)
+	}
+	body := fmt.Sprintf("To: %s\r\nSubject: Ava\r\n\r\n%s\r\n", user.Email,
+		msg.Sentence)
+	return smtp.SendMail(t.addr, t.auth, t.from, []string{user.Email},
+		[]byte(body))
+}
@@ -0,0 +1,76 @@
+package dt
+
+import (
+	"regexp"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Dialect identifies which SQL backend a Store talks to. Placeholder syntax
+// (`$1` vs `?`) and how JSON array columns are queried (`jsonb_array_length`
+// vs `json_each`) both differ between them.
+type Dialect int
+
+const (
+	DialectPostgres Dialect = iota
+	DialectSQLite
+)
+
+// Store wraps a *sqlx.DB with the dialect-specific SQL needed by the
+// pronoun-resolution and city-lookup paths, so the rest of Abot can run
+// against Postgres in production or a single-file SQLite/libSQL DB for
+// embedded and edge deployments without sprinkling dialect checks through
+// business logic.
+type Store interface {
+	// DB returns the underlying sqlx handle for queries that don't need
+	// dialect translation.
+	DB() *sqlx.DB
+
+	// Dialect reports which backend this Store is speaking to.
+	Dialect() Dialect
+
+	// Rebind translates a query written with Postgres-style `$1`
+	// placeholders into the bindvar syntax this Store's dialect expects.
+	Rebind(query string) string
+
+	// JSONArrayLength returns a SQL expression that counts the elements
+	// of a JSON array column (Abot stores `objects`, `actors`, `times`,
+	// and `places` this way).
+	JSONArrayLength(column string) string
+}
+
+// store is the shared implementation behind NewPostgresStore and
+// NewSQLiteStore. The two constructors differ only in the Dialect they set.
+type store struct {
+	db      *sqlx.DB
+	dialect Dialect
+}
+
+func (s *store) DB() *sqlx.DB     { return s.db }
+func (s *store) Dialect() Dialect { return s.dialect }
+
+// regexDollarBindVar matches a Postgres-style `$1`, `$2`, ... placeholder.
+var regexDollarBindVar = regexp.MustCompile(`\$\d+`)
+
+// Rebind translates query's `$N` placeholders to `?`, SQLite's bindvar
+// syntax. It does this translation itself rather than delegating to
+// sqlx.DB.Rebind: sqlx only recognizes `?`-style source queries and
+// rewrites them into whatever its registered driver name implies, and our
+// modernc.org/sqlite driver is registered as "sqlite" rather than the
+// "sqlite3" name sqlx's bindType table knows about, so that delegation was
+// silently a no-op for two-or-more-placeholder queries. Placeholders are
+// replaced in the order they appear and their numbers are ignored, so each
+// `$N` must appear once and in ascending order.
+func (s *store) Rebind(query string) string {
+	if s.dialect == DialectSQLite {
+		return regexDollarBindVar.ReplaceAllString(query, "?")
+	}
+	return s.db.Rebind(query)
+}
+
+func (s *store) JSONArrayLength(column string) string {
+	if s.dialect == DialectSQLite {
+		return "(SELECT count(*) FROM json_each(" + column + "))"
+	}
+	return "jsonb_array_length(" + column + ")"
+}
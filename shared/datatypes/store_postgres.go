@@ -0,0 +1,15 @@
+package dt
+
+import (
+	"github.com/jmoiron/sqlx"
+
+	// register the Postgres driver used by NewPostgresStore
+	_ "github.com/lib/pq"
+)
+
+// NewPostgresStore wraps an already-open Postgres *sqlx.DB in a Store. It
+// does not open the connection itself so callers can reuse a *sqlx.DB set up
+// with whatever pooling/TLS options their deployment needs.
+func NewPostgresStore(db *sqlx.DB) Store {
+	return &store{db: db, dialect: DialectPostgres}
+}
@@ -0,0 +1,29 @@
+package dt
+
+import (
+	"github.com/jmoiron/sqlx"
+
+	// modernc.org/sqlite is a cgo-free driver, which keeps Abot's embedded
+	// deployment a single static binary plus one DB file.
+	_ "modernc.org/sqlite"
+)
+
+// OpenSQLiteStore opens (creating if necessary) a single-file SQLite/libSQL
+// database at path and wraps it in a Store. This is the entry point for
+// running Abot without a Postgres server, e.g. for embedded or edge
+// deployments.
+func OpenSQLiteStore(path string) (Store, error) {
+	db, err := sqlx.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+	return NewSQLiteStore(db), nil
+}
+
+// NewSQLiteStore wraps an already-open SQLite *sqlx.DB in a Store.
+func NewSQLiteStore(db *sqlx.DB) Store {
+	return &store{db: db, dialect: DialectSQLite}
+}
@@ -0,0 +1,34 @@
+package dt
+
+import "testing"
+
+func TestStoreRebindSQLite(t *testing.T) {
+	s := &store{dialect: DialectSQLite}
+	cases := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT * FROM t WHERE id=$1", "SELECT * FROM t WHERE id=?"},
+		{
+			"DELETE FROM device_tokens WHERE platform=$1 AND token=$2",
+			"DELETE FROM device_tokens WHERE platform=? AND token=?",
+		},
+		{"SELECT 1", "SELECT 1"},
+	}
+	for _, c := range cases {
+		if got := s.Rebind(c.query); got != c.want {
+			t.Errorf("Rebind(%q) = %q, want %q", c.query, got, c.want)
+		}
+	}
+}
+
+func TestStoreJSONArrayLength(t *testing.T) {
+	pg := &store{dialect: DialectPostgres}
+	if got := pg.JSONArrayLength("objects"); got != "jsonb_array_length(objects)" {
+		t.Errorf("postgres JSONArrayLength = %q", got)
+	}
+	lite := &store{dialect: DialectSQLite}
+	if got := lite.JSONArrayLength("objects"); got != "(SELECT count(*) FROM json_each(objects))" {
+		t.Errorf("sqlite JSONArrayLength = %q", got)
+	}
+}
@@ -0,0 +1,142 @@
+// Package textin preprocesses raw user input before it reaches the stemmer
+// and classifier, so messages delivered as HTML (email, web chat, Slack
+// blocks) don't turn tags into bogus tokens.
+package textin
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Link is a hyperlink found in an HTML message, recorded in Meta so plugins
+// can still inspect links after the message has been flattened to plain
+// text.
+type Link struct {
+	Text string
+	Href string
+}
+
+// Meta records what Normalize observed about the original message.
+type Meta struct {
+	// WasHTML reports whether raw was detected as HTML and converted.
+	WasHTML bool
+	// Links lists every <a href> found, in document order.
+	Links []Link
+}
+
+var smartQuotes = strings.NewReplacer(
+	"‘", "'", "’", "'",
+	"“", `"`, "”", `"`,
+	"–", "-", "—", "-",
+)
+
+var regexWhitespace = regexp.MustCompile(`[ \t]+`)
+var regexBlankLines = regexp.MustCompile(`\n{3,}`)
+
+// Normalize converts raw into clean plain text suitable for stemming and
+// classification, along with Meta describing what it found. If mime
+// indicates HTML (or raw looks like HTML when mime is empty/unknown), tags
+// are converted to readable text: headings/paragraphs/list items each get
+// their own line, list items are prefixed with "- ", links become "text
+// (url)", and <br> becomes a newline. <script>/<style> blocks and <img>
+// tags (which cover tracking pixels; Abot has nowhere to render an image
+// inline) are dropped entirely. Smart quotes and em/en dashes are folded to
+// ASCII and runs of whitespace are collapsed.
+func Normalize(raw string, mime string) (string, Meta) {
+	var meta Meta
+	clean := raw
+	if isHTML(raw, mime) {
+		meta.WasHTML = true
+		clean, meta.Links = htmlToText(raw)
+	}
+	clean = smartQuotes.Replace(clean)
+	clean = regexWhitespace.ReplaceAllString(clean, " ")
+	clean = regexBlankLines.ReplaceAllString(clean, "\n\n")
+	return strings.TrimSpace(clean), meta
+}
+
+// isHTML reports whether raw should be run through the HTML-to-text
+// converter, either because mime says so explicitly or because raw contains
+// an opening, closing, or self-closing tag (e.g. a lone <br> or <img>).
+func isHTML(raw, mime string) bool {
+	if strings.Contains(strings.ToLower(mime), "html") {
+		return true
+	}
+	return regexHTMLTag.MatchString(raw)
+}
+
+var regexHTMLTag = regexp.MustCompile(`</?[a-zA-Z][^<>]*>`)
+
+// htmlToText walks an HTML document, emitting readable plain text and
+// collecting every link it finds along the way.
+func htmlToText(raw string) (string, []Link) {
+	doc, err := html.Parse(strings.NewReader(raw))
+	if err != nil {
+		return raw, nil
+	}
+	var b strings.Builder
+	var links []Link
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script", "style", "img":
+				return
+			case "br":
+				b.WriteString("\n")
+				return
+			case "a":
+				href := attr(n, "href")
+				text := textContent(n)
+				if href == "" {
+					b.WriteString(text)
+					return
+				}
+				b.WriteString(text + " (" + href + ")")
+				links = append(links, Link{Text: text, Href: href})
+				return
+			case "li":
+				b.WriteString("- ")
+				defer b.WriteString("\n")
+			case "p", "div", "h1", "h2", "h3", "h4", "h5", "h6", "tr":
+				defer b.WriteString("\n")
+			}
+		}
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return b.String(), links
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// textContent returns the concatenated text of n's descendants, ignoring
+// any markup (used to render an <a> tag's visible label).
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
@@ -0,0 +1,82 @@
+package textin
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		name    string
+		raw     string
+		mime    string
+		want    string
+		wantSig bool // whether meta.WasHTML should be true
+	}{
+		{
+			name: "plain text passthrough",
+			raw:  "hey what's up",
+			want: "hey what's up",
+		},
+		{
+			name:    "strips script and style blocks",
+			raw:     "<p>hi<script>evil()</script><style>.a{}</style></p>",
+			want:    "hi",
+			wantSig: true,
+		},
+		{
+			name:    "br becomes newline",
+			raw:     "line one<br>line two",
+			want:    "line one\nline two",
+			wantSig: true,
+		},
+		{
+			name:    "link rendered as text (url)",
+			raw:     `<a href="https://example.com">click here</a>`,
+			want:    "click here (https://example.com)",
+			wantSig: true,
+		},
+		{
+			name:    "drops tracking pixel img",
+			raw:     `hi<img src="https://track.example.com/p.gif">bye`,
+			want:    "hibye",
+			wantSig: true,
+		},
+		{
+			name: "folds smart quotes",
+			raw:  "“it’s great”",
+			want: `"it's great"`,
+		},
+		{
+			name: "collapses whitespace",
+			raw:  "too   many     spaces",
+			want: "too many spaces",
+		},
+		{
+			name:    "list items render one per line",
+			raw:     "<ul><li>one</li><li>two</li></ul>",
+			want:    "- one\n- two",
+			wantSig: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, meta := Normalize(c.raw, c.mime)
+			if got != c.want {
+				t.Errorf("Normalize(%q) = %q, want %q", c.raw, got, c.want)
+			}
+			if meta.WasHTML != c.wantSig {
+				t.Errorf("Normalize(%q) meta.WasHTML = %v, want %v",
+					c.raw, meta.WasHTML, c.wantSig)
+			}
+		})
+	}
+}
+
+func TestNormalizeCollectsLinks(t *testing.T) {
+	raw := `<p>see <a href="https://a.example">a</a> and <a href="https://b.example">b</a></p>`
+	_, meta := Normalize(raw, "text/html")
+	if len(meta.Links) != 2 {
+		t.Fatalf("got %d links, want 2", len(meta.Links))
+	}
+	if meta.Links[0].Href != "https://a.example" || meta.Links[1].Href != "https://b.example" {
+		t.Errorf("unexpected links: %+v", meta.Links)
+	}
+}
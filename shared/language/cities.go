@@ -0,0 +1,234 @@
+package language
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/itsabot/abot/shared/datatypes"
+	"github.com/lib/pq"
+)
+
+// ExtractCitiesOptions configures ExtractCities' search.
+type ExtractCitiesOptions struct {
+	// CountryCodes restricts matches to this allow-list of ISO-3166
+	// alpha-2 codes. A nil/empty slice means no restriction, matching
+	// cities from any country.
+	CountryCodes []string
+
+	// Locale selects which preposition anchors ("at/in/on" and their
+	// equivalents in other languages) signal that a location follows.
+	// Defaults to "en".
+	Locale string
+}
+
+// CityMatch pairs a city with a confidence score in [0, 1] for how well it
+// matched the input, so callers can disambiguate ambiguous names like
+// "Paris, TX" vs "Paris, FR".
+type CityMatch struct {
+	City       dt.City
+	Confidence float64
+}
+
+// citySimilarityThreshold is the minimum trigram similarity (Postgres) a
+// candidate must clear to be considered a match at all; below it, two
+// strings share too few trigrams to be the same city.
+const citySimilarityThreshold = 0.3
+
+// prepositionsByLocale lists the words that signal "a location follows",
+// per locale. Abot only ships a handful; plugin authors needing more can
+// still pass their own pre-tokenized Msg.Stems.
+var prepositionsByLocale = map[string][]string{
+	"en": {"at", "in", "on"},
+	"es": {"en", "a"},
+	"fr": {"à", "en", "dans"},
+	"de": {"in", "bei", "an"},
+}
+
+// ExtractCities searches a user's message for city names. It tokenizes into
+// unigrams, bigrams, and trigrams following a preposition anchor ("in São
+// Paulo", "at New York City"), Unicode-normalizes (NFKD, folding diacritics)
+// for matching while preserving the original spelling in the returned City,
+// and queries a trigram (Postgres pg_trgm) or full-text (SQLite FTS5) index
+// so near-misspellings still match.
+func ExtractCities(ctx context.Context, s dt.Store, in *dt.Msg,
+	opts ExtractCitiesOptions) ([]CityMatch, error) {
+	preps := prepositionsByLocale[opts.Locale]
+	if preps == nil {
+		preps = prepositionsByLocale["en"]
+	}
+
+	words := tokenizeForCities(in.Sentence)
+
+	var start int
+	for i := range in.Stems {
+		for _, p := range preps {
+			if in.Stems[i] == p {
+				start = i
+			}
+		}
+	}
+
+	normalized := make([]string, len(words))
+	for i, w := range words {
+		normalized[i] = normalizeCityText(w)
+	}
+
+	grams := cityNgrams(normalized, start)
+	if len(grams) == 0 {
+		return nil, nil
+	}
+
+	seen := map[string]CityMatch{}
+	for _, candidate := range grams {
+		var found []CityMatch
+		var err error
+		if s.Dialect() == dt.DialectPostgres {
+			found, err = queryCitiesTrigram(ctx, s, candidate, opts.CountryCodes)
+		} else {
+			found, err = queryCitiesFTS(ctx, s, candidate, opts.CountryCodes)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range found {
+			key := m.City.CountryCode + "\x00" + m.City.Name
+			if existing, ok := seen[key]; !ok || m.Confidence > existing.Confidence {
+				seen[key] = m
+			}
+		}
+	}
+
+	matches := make([]CityMatch, 0, len(seen))
+	for _, m := range seen {
+		matches = append(matches, m)
+	}
+	return matches, nil
+}
+
+// queryCitiesTrigram matches candidate against cities.name_normalized using
+// Postgres' pg_trgm similarity operator (`%`), so close misspellings
+// (missing accents, transposed letters) still surface a row. Confidence is
+// the similarity score pg_trgm itself computed.
+func queryCitiesTrigram(ctx context.Context, s dt.Store, candidate string,
+	countryCodes []string) ([]CityMatch, error) {
+	q := `SELECT name, countrycode, similarity(name_normalized, $1) AS sim
+	      FROM cities
+	      WHERE name_normalized % $1 AND similarity(name_normalized, $1) >= $2`
+	args := []interface{}{candidate, citySimilarityThreshold}
+	if len(countryCodes) > 0 {
+		q += ` AND countrycode = ANY($3)`
+		args = append(args, pq.Array(countryCodes))
+	}
+	q += ` ORDER BY sim DESC`
+	rows, err := s.DB().QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var matches []CityMatch
+	for rows.Next() {
+		var city dt.City
+		var sim float64
+		if err := rows.Scan(&city.Name, &city.CountryCode, &sim); err != nil {
+			return nil, err
+		}
+		matches = append(matches, CityMatch{City: city, Confidence: sim})
+	}
+	return matches, rows.Err()
+}
+
+// queryCitiesFTS matches candidate against the cities_fts FTS5 virtual
+// table, which SQLite scores with bm25 (more negative is a better match).
+// Confidence is derived from that rank, rescaled into (0, 1].
+func queryCitiesFTS(ctx context.Context, s dt.Store, candidate string,
+	countryCodes []string) ([]CityMatch, error) {
+	q := `SELECT c.name, c.countrycode, bm25(cities_fts) AS rank
+	      FROM cities_fts
+	      JOIN cities c ON c.rowid = cities_fts.rowid
+	      WHERE cities_fts MATCH ?`
+	args := []interface{}{candidate}
+	if len(countryCodes) > 0 {
+		placeholders := make([]string, len(countryCodes))
+		for i, cc := range countryCodes {
+			placeholders[i] = "?"
+			args = append(args, cc)
+		}
+		q += ` AND c.countrycode IN (` + strings.Join(placeholders, ",") + `)`
+	}
+	q += ` ORDER BY rank`
+	rows, err := s.DB().QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var matches []CityMatch
+	for rows.Next() {
+		var city dt.City
+		var rank float64
+		if err := rows.Scan(&city.Name, &city.CountryCode, &rank); err != nil {
+			return nil, err
+		}
+		matches = append(matches, CityMatch{City: city, Confidence: bm25Confidence(rank)})
+	}
+	return matches, rows.Err()
+}
+
+// bm25Confidence rescales an FTS5 bm25 rank (more negative = better match,
+// 0 = no relevance) into a [0, 1) confidence score comparable to pg_trgm's
+// similarity output, so a better match gets a higher confidence.
+func bm25Confidence(rank float64) float64 {
+	if rank > 0 {
+		rank = 0
+	}
+	return 1 - 1/(1-rank)
+}
+
+// tokenizeForCities strips punctuation from s while keeping every letter
+// Unicode considers part of a word (so accented city names like "São
+// Paulo" or "Zürich" survive intact) and returns the remaining words.
+func tokenizeForCities(s string) []string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsSpace(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Fields(b.String())
+}
+
+// normalizeCityText folds s to NFKD and strips combining marks (accents),
+// returning a lowercase form suitable for matching against name_normalized.
+// The original, unmodified text is always what's returned to callers via
+// City.Name.
+func normalizeCityText(s string) string {
+	t := norm.NFKD.String(s)
+	var b strings.Builder
+	for _, r := range t {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// cityNgrams builds unigrams, bigrams, and trigrams of words starting at
+// startIndex, the widest net ExtractCities casts over a sentence.
+func cityNgrams(words []string, startIndex int) []string {
+	var grams []string
+	for i := startIndex; i < len(words); i++ {
+		grams = append(grams, words[i])
+		if i+1 < len(words) {
+			grams = append(grams, words[i]+" "+words[i+1])
+		}
+		if i+2 < len(words) {
+			grams = append(grams, words[i]+" "+words[i+1]+" "+words[i+2])
+		}
+	}
+	return grams
+}
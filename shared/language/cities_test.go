@@ -0,0 +1,77 @@
+package language
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenizeForCities(t *testing.T) {
+	cases := []struct {
+		in   string
+		want []string
+	}{
+		{"I live in Zürich", []string{"I", "live", "in", "Zürich"}},
+		{"I'm flying to São Paulo, Brazil.", []string{"I", "m", "flying", "to", "São", "Paulo", "Brazil"}},
+		{"meet me at New York City", []string{"meet", "me", "at", "New", "York", "City"}},
+	}
+	for _, c := range cases {
+		got := tokenizeForCities(c.in)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("tokenizeForCities(%q) = %#v, want %#v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestNormalizeCityText(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"Zürich", "zurich"},
+		{"São", "sao"},
+		{"Paulo", "paulo"},
+		{"NEW YORK", "new york"},
+	}
+	for _, c := range cases {
+		if got := normalizeCityText(c.in); got != c.want {
+			t.Errorf("normalizeCityText(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestCityNgrams(t *testing.T) {
+	words := []string{"new", "york", "city"}
+	got := cityNgrams(words, 0)
+	want := []string{
+		"new", "new york", "new york city",
+		"york", "york city",
+		"city",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("cityNgrams = %#v, want %#v", got, want)
+	}
+}
+
+func TestCityNgramsRespectsStartIndex(t *testing.T) {
+	words := []string{"meet", "me", "at", "new", "york"}
+	got := cityNgrams(words, 3)
+	want := []string{"new", "new york", "york"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("cityNgrams = %#v, want %#v", got, want)
+	}
+}
+
+func TestBM25Confidence(t *testing.T) {
+	cases := []struct {
+		rank float64
+		want float64
+	}{
+		{0, 0},
+		{-1, 0.5},
+	}
+	for _, c := range cases {
+		if got := bm25Confidence(c.rank); got != c.want {
+			t.Errorf("bm25Confidence(%v) = %v, want %v", c.rank, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package language
+
+import (
+	"database/sql"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/itsabot/abot/shared/datatypes"
+	"github.com/itsabot/abot/core/log"
+)
+
+// minorUnits maps an ISO-4217 code to how many decimal places its minor unit
+// has, so a parsed float can be scaled into Money.Amount. Currencies not
+// listed default to 2, the common case (cents, pence, etc.).
+var minorUnits = map[string]int{
+	"JPY": 0,
+	"KRW": 0,
+	"VND": 0,
+	"BHD": 3,
+	"KWD": 3,
+	"OMR": 3,
+}
+
+// currencySymbols maps the symbols ExtractCurrency recognizes to the
+// ISO-4217 code they imply. Symbols are ambiguous across locales ($ alone
+// could be USD, CAD, AUD...); we default to the most common reading.
+var currencySymbols = map[string]string{
+	"$": "USD",
+	"€": "EUR",
+	"£": "GBP",
+	"¥": "JPY",
+	"₹": "INR",
+	"₩": "KRW",
+}
+
+// knownCurrencyCodes is the set of ISO-4217 codes isoCodeNextToAmount is
+// allowed to match. Without this, an ordinary 3-letter word adjacent to the
+// amount ("for," "the," "gas") would be mistaken for a currency code; this
+// isn't an exhaustive ISO-4217 list, just the codes Abot's users actually say.
+var knownCurrencyCodes = map[string]bool{
+	"USD": true, "EUR": true, "GBP": true, "JPY": true, "CNY": true,
+	"AUD": true, "CAD": true, "CHF": true, "INR": true, "KRW": true,
+	"VND": true, "BHD": true, "KWD": true, "OMR": true, "MXN": true,
+	"BRL": true, "ZAR": true, "SEK": true, "NOK": true, "DKK": true,
+	"NZD": true, "SGD": true, "HKD": true,
+}
+
+var regexCurrencySymbol = regexp.MustCompile(`[$€£¥₹₩]`)
+var regexCurrencyAmount = regexp.MustCompile(`[\d][\d.,]*`)
+
+// ExtractCurrency parses the first monetary amount in s, returning its value
+// in the currency's minor units along with its ISO-4217 code. It recognizes
+// leading or trailing currency symbols ($ € £ ¥ ₹ ₩), a leading or trailing
+// ISO-4217 code immediately adjacent to the amount ("10 USD", "JPY 500"),
+// and locale-aware thousand separators ("1,299.50" vs "1.299,50"). A code is
+// only accepted if it sits right next to the amount and is in
+// knownCurrencyCodes, so an unrelated word elsewhere in the sentence can't
+// be mistaken for one. A nil Money means no amount was found.
+func ExtractCurrency(s string) (*dt.Money, error) {
+	log.Debug("extracting currency")
+	loc := regexCurrencyAmount.FindStringIndex(s)
+	if loc == nil {
+		return nil, nil
+	}
+	raw := s[loc[0]:loc[1]]
+	val, err := parseLocaleAmount(raw)
+	if err != nil {
+		return nil, err
+	}
+	code := "USD"
+	if sym := regexCurrencySymbol.FindString(s); sym != "" {
+		code = currencySymbols[sym]
+	} else if iso := isoCodeNextToAmount(s, loc[0], loc[1]); iso != "" {
+		code = iso
+	}
+	minor, ok := minorUnits[code]
+	if !ok {
+		minor = 2
+	}
+	scale := 1.0
+	for i := 0; i < minor; i++ {
+		scale *= 10
+	}
+	log.Debug("found value", val, code)
+	return &dt.Money{
+		Amount:   int64(val*scale + 0.5),
+		Currency: code,
+	}, nil
+}
+
+// isoCodeNextToAmount looks for a 3-letter word directly touching (at most
+// one space away from) the amount at s[start:end], in either direction, and
+// returns it uppercased if it's a recognized ISO-4217 code. It returns ""
+// if no adjacent word is found or the word isn't a known code.
+func isoCodeNextToAmount(s string, start, end int) string {
+	before := strings.TrimSuffix(s[:start], " ")
+	if i := strings.LastIndex(before, " "); i >= 0 {
+		before = before[i+1:]
+	}
+	if code := strings.ToUpper(strings.TrimSpace(before)); len(code) == 3 && knownCurrencyCodes[code] {
+		return code
+	}
+	after := strings.TrimPrefix(s[end:], " ")
+	if i := strings.Index(after, " "); i >= 0 {
+		after = after[:i]
+	}
+	if code := strings.ToUpper(strings.TrimSpace(after)); len(code) == 3 && knownCurrencyCodes[code] {
+		return code
+	}
+	return ""
+}
+
+// parseLocaleAmount normalizes a number written with either US-style
+// ("1,299.50") or European-style ("1.299,50") grouping into a float64. The
+// separator that appears last is treated as the decimal point; the other is
+// stripped as a thousands grouping.
+func parseLocaleAmount(s string) (float64, error) {
+	lastComma := strings.LastIndex(s, ",")
+	lastDot := strings.LastIndex(s, ".")
+	switch {
+	case lastComma > lastDot:
+		s = strings.Replace(s, ".", "", -1)
+		s = strings.Replace(s, ",", ".", 1)
+	case lastDot > lastComma:
+		s = strings.Replace(s, ",", "", -1)
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// ExtractCurrencyLegacy preserves the pre-Money API: it returns an amount in
+// cents and silently assumes USD, matching the original ExtractCurrency's
+// behavior for callers that haven't migrated to the ISO-4217-aware version.
+func ExtractCurrencyLegacy(s string) sql.NullInt64 {
+	n := sql.NullInt64{}
+	m, err := ExtractCurrency(s)
+	if err != nil || m == nil {
+		return n
+	}
+	// Rescale from m.Currency's minor units to cents, dropping the code.
+	minor, ok := minorUnits[m.Currency]
+	if !ok {
+		minor = 2
+	}
+	cents := float64(m.Amount) * 100
+	for i := 0; i < minor; i++ {
+		cents /= 10
+	}
+	n.Int64 = int64(cents + 0.5)
+	n.Valid = true
+	return n
+}
@@ -0,0 +1,52 @@
+package language
+
+import "testing"
+
+func TestExtractCurrencyIgnoresUnrelatedWords(t *testing.T) {
+	m, err := ExtractCurrency("pay you 20 for the cab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m == nil {
+		t.Fatal("ExtractCurrency() = nil, want a Money")
+	}
+	if m.Currency != "USD" {
+		t.Errorf("Currency = %q, want USD (not a stray 3-letter word)", m.Currency)
+	}
+	if m.Amount != 2000 {
+		t.Errorf("Amount = %d, want 2000", m.Amount)
+	}
+}
+
+func TestExtractCurrencyRecognizesAdjacentCode(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"send 500 JPY", "JPY"},
+		{"USD 10 please", "USD"},
+		{"transfer 25 EUR now", "EUR"},
+	}
+	for _, c := range cases {
+		m, err := ExtractCurrency(c.in)
+		if err != nil {
+			t.Fatalf("%q: %v", c.in, err)
+		}
+		if m == nil {
+			t.Fatalf("%q: ExtractCurrency() = nil", c.in)
+		}
+		if m.Currency != c.want {
+			t.Errorf("ExtractCurrency(%q).Currency = %q, want %q", c.in, m.Currency, c.want)
+		}
+	}
+}
+
+func TestExtractCurrencyNoAmount(t *testing.T) {
+	m, err := ExtractCurrency("no numbers here")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != nil {
+		t.Errorf("ExtractCurrency() = %+v, want nil", m)
+	}
+}
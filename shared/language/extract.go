@@ -1,6 +1,7 @@
 package language
 
 import (
+	"context"
 	"database/sql"
 	"encoding/xml"
 	"io/ioutil"
@@ -17,34 +18,9 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
-var regexCurrency = regexp.MustCompile(`\d+\.?\d*`)
 var regexNum = regexp.MustCompile(`\d+`)
 var regexNonWords = regexp.MustCompile(`[^\w\s]`)
 
-// ExtractCurrency returns a pointer to a string to allow a user a simple check
-// to see if currency text was found. If the response is nil, no currency was
-// found. This API design also maintains consistency when we want to extract and
-// return a struct (which should be returned as a pointer).
-func ExtractCurrency(s string) sql.NullInt64 {
-	log.Debug("extracting currency")
-	n := sql.NullInt64{
-		Int64: 0,
-		Valid: false,
-	}
-	s = regexCurrency.FindString(s)
-	if len(s) == 0 {
-		return n
-	}
-	val, err := strconv.ParseFloat(s, 64)
-	if err != nil {
-		return n
-	}
-	log.Debug("found value", val)
-	n.Int64 = int64(val * 100)
-	n.Valid = true
-	return n
-}
-
 // ExtractYesNo determines whether a string (typically a sentence sent by a
 // user to Abot) contains a Yes or No response. This is useful for plugins to
 // determine a user's answer to a Yes/No question.
@@ -76,9 +52,21 @@ func ExtractYesNo(s string) sql.NullBool {
 
 // ExtractAddress will return an address from a user's message, whether it's a
 // labeled address (e.g. "home", "office"), or a full U.S. address (e.g. 100
-// Penn St., CA 90000)
+// Penn St., CA 90000).
+//
+// ExtractAddress has no way to cancel the USPS lookup it may perform, so
+// prefer ExtractAddressContext in new code.
 func ExtractAddress(db *sqlx.DB, u *dt.User, s string) (*dt.Address, bool,
 	error) {
+	return ExtractAddressContext(context.Background(), db, u, s)
+}
+
+// ExtractAddressContext behaves like ExtractAddress, but aborts the USPS
+// validation request as soon as ctx is canceled or its deadline passes. The
+// DB lookup of a historical address (u.GetAddress) does not take ctx and is
+// not aborted.
+func ExtractAddressContext(ctx context.Context, db *sqlx.DB, u *dt.User,
+	s string) (*dt.Address, bool, error) {
 	addr, err := address.Parse(s)
 	if err != nil {
 		// check DB for historical information associated with that user
@@ -130,7 +118,11 @@ func ExtractAddress(db *sqlx.DB, u *dt.User, s string) (*dt.Address, bool,
 	log.Debug(string(xmlAddr))
 	ul := "https://secure.shippingapis.com/ShippingAPI.dll?API=Verify&XML="
 	ul += url.QueryEscape(string(xmlAddr))
-	response, err := http.Get(ul)
+	req, err := http.NewRequest(http.MethodGet, ul, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	response, err := doRequest(ctx, req)
 	if err != nil {
 		return nil, false, err
 	}
@@ -194,61 +186,3 @@ func ExtractCount(s string) sql.NullInt64 {
 	n.Valid = true
 	return n
 }
-
-// ExtractCities efficiently from a user's message.
-func ExtractCities(db *sqlx.DB, in *dt.Msg) ([]dt.City, error) {
-	// Interface type is used to expand the args in db.Select below.
-	// Although we're only storing strings, []string{} doesn't work.
-	var args []interface{}
-
-	// Look for "at", "in", "on" prepositions to signal that locations
-	// follow, skipping everything before
-	var start int
-	for i := range in.Stems {
-		switch in.Stems[i] {
-		case "at", "in", "on":
-			start = i
-			break
-		}
-	}
-
-	// Prepare sentence for iteration
-	tmp := regexNonWords.ReplaceAllString(in.Sentence, "")
-	words := strings.Fields(tmp)
-
-	// Iterate through words and bigrams to assemble a DB query
-	for i := start; i < len(words); i++ {
-		args = append(args, words[i])
-	}
-	bgs := bigrams(words, start)
-	for i := 0; i < len(bgs); i++ {
-		args = append(args, bgs[i])
-	}
-
-	cities := []dt.City{}
-	q := `SELECT name, countrycode FROM cities WHERE countrycode='US' AND name IN (?) ORDER BY LENGTH(name) DESC`
-	query, arguments, err := sqlx.In(q, args)
-	query = db.Rebind(query)
-	rows, err := db.Query(query, arguments...)
-	if err != nil {
-		return nil, err
-	}
-	for rows.Next() {
-		city := dt.City{}
-		if err = rows.Scan(&city.Name, &city.CountryCode); err != nil {
-			return nil, err
-		}
-		cities = append(cities, city)
-	}
-	if err = rows.Close(); err != nil {
-		return nil, err
-	}
-	return cities, nil
-}
-
-func bigrams(words []string, startIndex int) (bigrams []string) {
-	for i := startIndex; i < len(words)-1; i++ {
-		bigrams = append(bigrams, words[i]+" "+words[i+1])
-	}
-	return bigrams
-}
@@ -0,0 +1,63 @@
+package language
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"time"
+)
+
+// httpClient is shared by every Extract function that talks to an external
+// API (currently just ExtractAddressContext's USPS lookup) so they all get
+// the same timeouts, connection pooling, and TLS handshake limits instead of
+// relying on http.Get's zero-value client, which never times out.
+var httpClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 5 * time.Second,
+	},
+}
+
+// maxRetries bounds the retry-with-backoff loop in doRequest. 5xx responses
+// from a flaky upstream (USPS's API included) are worth a couple of retries;
+// anything else is returned to the caller immediately.
+const maxRetries = 3
+
+// doRequest issues req with httpClient, retrying with exponential backoff on
+// 5xx responses, and aborting early if ctx is canceled or its deadline
+// passes.
+func doRequest(ctx context.Context, req *http.Request) (*http.Response, error) {
+	req = req.WithContext(ctx)
+	var resp *http.Response
+	var err error
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		resp, err = httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		if resp.StatusCode < 500 {
+			return resp, nil
+		}
+		if err = resp.Body.Close(); err != nil {
+			return nil, err
+		}
+		backoff := time.Duration(math.Pow(2, float64(attempt))) * 100 * time.Millisecond
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+	return resp, nil
+}